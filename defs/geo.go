@@ -0,0 +1,41 @@
+package defs
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth, used by GreatCircleDistance.
+const earthRadiusKm = 6371.0
+
+// Point is a location expressed as latitude/longitude in decimal degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// GreatCircleDistance returns the haversine distance between a and b, in
+// kilometers.
+func GreatCircleDistance(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h))
+}
+
+// Closest returns the point in candidates nearest to origin, along with its
+// distance in kilometers. It panics if candidates is empty.
+func Closest(origin Point, candidates []Point) (Point, float64) {
+	best := candidates[0]
+	bestDist := GreatCircleDistance(origin, best)
+
+	for _, c := range candidates[1:] {
+		if d := GreatCircleDistance(origin, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+
+	return best, bestDist
+}
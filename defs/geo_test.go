@@ -0,0 +1,31 @@
+package defs
+
+import "testing"
+
+func TestGreatCircleDistance(t *testing.T) {
+	// London to Paris is ~344 km via great-circle distance.
+	london := Point{Lat: 51.5074, Lon: -0.1278}
+	paris := Point{Lat: 48.8566, Lon: 2.3522}
+
+	if d := GreatCircleDistance(london, paris); d < 340 || d > 350 {
+		t.Errorf("GreatCircleDistance(london, paris) = %.2f, want ~344", d)
+	}
+
+	if d := GreatCircleDistance(london, london); d != 0 {
+		t.Errorf("GreatCircleDistance(london, london) = %.2f, want 0", d)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	origin := Point{Lat: 0, Lon: 0}
+	near := Point{Lat: 1, Lon: 1}
+	far := Point{Lat: 40, Lon: 40}
+
+	best, dist := Closest(origin, []Point{far, near})
+	if best != near {
+		t.Errorf("Closest() = %+v, want %+v", best, near)
+	}
+	if dist <= 0 {
+		t.Errorf("Closest() distance = %.2f, want > 0", dist)
+	}
+}
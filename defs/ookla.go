@@ -0,0 +1,196 @@
+package defs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ooklaServerListURL is the static server list speedtest.net itself uses as
+// a fallback when the dynamic API is unavailable; it's good enough to rank
+// candidates by distance.
+const ooklaServerListURL = "https://www.speedtest.net/speedtest-servers-static.php"
+
+// ooklaHTTPTimeout bounds every request an OoklaServer issues, so one
+// unresponsive speedtest.net server can't stall ProbeLatency or a
+// download/upload pass indefinitely.
+const ooklaHTTPTimeout = 10 * time.Second
+
+// OoklaServer is a speedtest.net backend, selected via --backend. It
+// resolves the latency, download and upload URLs of one server and carries
+// the HTTP client to use against it; it does not implement the LibreSpeed
+// Server's methods (up-check, ping/jitter, download, upload), which
+// CliSpeedTestOokla performs itself against these URLs.
+type OoklaServer struct {
+	Name    string
+	Host    string
+	Sponsor string
+	Point   Point
+
+	client *http.Client
+}
+
+type ooklaServerListXML struct {
+	Servers []struct {
+		URL     string  `xml:"url,attr"`
+		Lat     float64 `xml:"lat,attr"`
+		Lon     float64 `xml:"lon,attr"`
+		Name    string  `xml:"name,attr"`
+		Sponsor string  `xml:"sponsor,attr"`
+		Host    string  `xml:"host,attr"`
+	} `xml:"servers>server"`
+}
+
+// FetchOoklaServers downloads and parses the public speedtest.net server
+// list.
+func FetchOoklaServers() ([]OoklaServer, error) {
+	client := &http.Client{Timeout: ooklaHTTPTimeout}
+	resp, err := client.Get(ooklaServerListURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Ookla server list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Ookla server list: %w", err)
+	}
+
+	var parsed ooklaServerListXML
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse Ookla server list: %w", err)
+	}
+
+	servers := make([]OoklaServer, 0, len(parsed.Servers))
+	for _, s := range parsed.Servers {
+		servers = append(servers, OoklaServer{
+			Name:    s.Name,
+			Host:    s.Host,
+			Sponsor: s.Sponsor,
+			Point:   Point{Lat: s.Lat, Lon: s.Lon},
+			client:  &http.Client{Timeout: ooklaHTTPTimeout},
+		})
+	}
+
+	return servers, nil
+}
+
+// ClosestOoklaServers ranks servers by great-circle distance from origin and
+// returns the nearest n, nearest first, using the Closest helper.
+func ClosestOoklaServers(origin Point, servers []OoklaServer, n int) []OoklaServer {
+	remaining := make([]OoklaServer, len(servers))
+	copy(remaining, servers)
+
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+
+	result := make([]OoklaServer, 0, n)
+	for len(result) < n {
+		points := make([]Point, len(remaining))
+		for i, s := range remaining {
+			points[i] = s.Point
+		}
+
+		best, _ := Closest(origin, points)
+		for i, p := range points {
+			if p == best {
+				result = append(result, remaining[i])
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// ProbeLatency picks the lowest-latency server among candidates by issuing a
+// latency.txt GET against each one.
+func ProbeLatency(candidates []OoklaServer) (OoklaServer, float64, error) {
+	if len(candidates) == 0 {
+		return OoklaServer{}, 0, fmt.Errorf("no candidate servers to probe")
+	}
+
+	best := candidates[0]
+	bestLatency := -1.0
+
+	for _, s := range candidates {
+		u, err := s.latencyURL()
+		if err != nil {
+			continue
+		}
+
+		start := time.Now()
+		resp, err := s.HTTPClient().Get(u.String())
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		latency := float64(time.Since(start).Microseconds()) / 1000
+
+		if bestLatency < 0 || latency < bestLatency {
+			best, bestLatency = s, latency
+		}
+	}
+
+	if bestLatency < 0 {
+		return OoklaServer{}, 0, fmt.Errorf("none of the %d candidate servers responded", len(candidates))
+	}
+
+	return best, bestLatency, nil
+}
+
+// HTTPClient returns the client this server was resolved with, so callers
+// in other packages issue requests with the same timeout FetchOoklaServers
+// configured instead of falling back to http.DefaultClient.
+func (s OoklaServer) HTTPClient() *http.Client {
+	if s.client == nil {
+		return &http.Client{Timeout: ooklaHTTPTimeout}
+	}
+	return s.client
+}
+
+// baseURL resolves s.Host to a URL. The server list hands out bare
+// "host:port" strings almost exclusively; url.Parse on its own can't be
+// trusted to detect those, since e.g. url.Parse("speedtest.example.com:8080")
+// parses the part before the colon as a scheme rather than a host, so the
+// scheme is checked explicitly instead of relying on its inference.
+func (s OoklaServer) baseURL() (*url.URL, error) {
+	host := s.Host
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return url.Parse(host)
+}
+
+func (s OoklaServer) latencyURL() (*url.URL, error) {
+	u, err := s.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/latency.txt"
+	return u, nil
+}
+
+func (s OoklaServer) DownloadURL(size int) (*url.URL, error) {
+	u, err := s.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	u.Path = fmt.Sprintf("/random%dx%d.jpg", size, size)
+	return u, nil
+}
+
+func (s OoklaServer) UploadURL() (*url.URL, error) {
+	u, err := s.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/upload.php"
+	return u, nil
+}
@@ -0,0 +1,29 @@
+package defs
+
+import "testing"
+
+func TestClosestOoklaServers(t *testing.T) {
+	origin := Point{Lat: 0, Lon: 0}
+	servers := []OoklaServer{
+		{Name: "far", Point: Point{Lat: 40, Lon: 40}},
+		{Name: "near", Point: Point{Lat: 1, Lon: 1}},
+		{Name: "mid", Point: Point{Lat: 10, Lon: 10}},
+	}
+
+	got := ClosestOoklaServers(origin, servers, 2)
+	if len(got) != 2 {
+		t.Fatalf("len(got) = %d, want 2", len(got))
+	}
+	if got[0].Name != "near" || got[1].Name != "mid" {
+		t.Errorf("got %q, %q in that order, want near, mid", got[0].Name, got[1].Name)
+	}
+}
+
+func TestClosestOoklaServersClampsN(t *testing.T) {
+	servers := []OoklaServer{{Name: "only"}}
+
+	got := ClosestOoklaServers(Point{}, servers, 10)
+	if len(got) != 1 {
+		t.Errorf("len(got) = %d, want 1", len(got))
+	}
+}
@@ -0,0 +1,48 @@
+package defs
+
+// Option* are the urfave/cli flag names CliSpeedTest and its callers read
+// off a *cli.Context. They live here, rather than next to the App
+// definition, so every package that needs to read or register a flag can
+// import defs without pulling in the CLI entry point itself.
+const (
+	// OptionServe is the <addr> a binary listens on to serve P2P speedtest
+	// clients instead of running a test itself.
+	OptionServe = "serve"
+
+	// OptionP2P is the <host:port> of a peer running in --serve mode to run
+	// a P2P test against, instead of the HTTP LibreSpeed backend. Mutually
+	// exclusive with --server.
+	OptionP2P = "p2p"
+
+	// OptionBackend selects which backend the CLI runs against:
+	// "librespeed" (the default) or "ookla".
+	OptionBackend = "backend"
+
+	// OptionClosest asks CliSpeedTestOokla to skip latency-probing a pool of
+	// nearby servers and go straight for the single nearest one by
+	// great-circle distance. The LibreSpeed path doesn't have a multi-server
+	// discovery step to plug this into yet, so this only affects --backend
+	// ookla for now.
+	OptionClosest = "closest"
+
+	// OptionProto renders the report as a ReportBatch protobuf instead of
+	// the default human-readable summary.
+	OptionProto = "proto"
+
+	// OptionProtoOut is the file --proto writes its output to; if empty,
+	// the protobuf bytes go to stdout instead.
+	OptionProtoOut = "proto-out"
+
+	// OptionBufferbloat turns on latency-under-load measurement: a second
+	// ping sampler runs alongside Download/Upload so the report can grade
+	// how much the link's RTT degrades while loaded.
+	OptionBufferbloat = "bufferbloat"
+
+	// OptionServer is the comma separated list of LibreSpeed server IDs to
+	// test against. Mutually exclusive with OptionServe/OptionP2P.
+	OptionServer = "server"
+
+	// OptionDuration is how long, in seconds, each download/upload pass
+	// runs for.
+	OptionDuration = "duration"
+)
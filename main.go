@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/librespeed/speedtest-cli/defs"
+	"github.com/librespeed/speedtest-cli/speedtest"
+)
+
+// app wires the flags this binary understands to the Dispatch* functions;
+// the LibreSpeed CliSpeedTest path is not run from here, so --server is only
+// checked for conflicts with --serve/--p2p. Flags are grouped roughly in the order their backend was
+// added: the base LibreSpeed flags, then P2P, then the Ookla backend, then
+// report output modes, then bufferbloat.
+var app = &cli.App{
+	Name:  "speedtest-cli",
+	Usage: "Command line tool to measure network speed",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: defs.OptionServer, Usage: "Specify server ID(s) to test against, comma separated"},
+		&cli.IntFlag{Name: defs.OptionDuration, Value: 15, Usage: "Duration of the download/upload test in seconds"},
+		&cli.BoolFlag{Name: defs.OptionSimple, Usage: "Suppress verbose output, print only basic information"},
+		&cli.BoolFlag{Name: defs.OptionJSON, Usage: "Output result in JSON format"},
+		&cli.BoolFlag{Name: defs.OptionCSV, Usage: "Output result in CSV format"},
+
+		&cli.StringFlag{Name: defs.OptionServe, Usage: "Listen on <addr> and serve P2P speedtest clients instead of running a test"},
+		&cli.StringFlag{Name: defs.OptionP2P, Usage: "Run a P2P test against a peer listening in --serve mode, as <host:port>"},
+
+		&cli.StringFlag{Name: defs.OptionBackend, Value: "librespeed", Usage: "Backend to test against: librespeed or ookla"},
+		&cli.BoolFlag{Name: defs.OptionClosest, Usage: "With --backend ookla, skip latency-probing and use the single nearest server"},
+		&cli.IntFlag{Name: "concurrent", Value: 4, Usage: "Number of concurrent connections to use for the Ookla backend"},
+
+		&cli.BoolFlag{Name: defs.OptionProto, Usage: "Output result as a Protobuf ReportBatch instead of a human-readable summary"},
+		&cli.StringFlag{Name: defs.OptionProtoOut, Usage: "File to write the --proto report to (default: stdout)"},
+
+		&cli.BoolFlag{Name: defs.OptionBufferbloat, Usage: "Measure latency-under-load alongside download/upload and grade it A-F"},
+	},
+	Action: run,
+}
+
+// ooklaOrigin is the client location CliSpeedTestOokla ranks servers from.
+// The real geolocation lookup lives in the IP-info resolution CliSpeedTest
+// does for the LibreSpeed backend (not present in this build), so this is a
+// zero Point until that's threaded through here too - distance ranking still
+// runs, but measures distance from latitude 0, longitude 0 rather than from
+// the client.
+var ooklaOrigin defs.Point
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run checks the P2P dispatch first, since --serve/--p2p bypass the normal
+// LibreSpeed server-discovery flow entirely, then the Ookla backend.
+func run(c *cli.Context) error {
+	if handled, err := speedtest.DispatchP2P(c); handled {
+		return err
+	}
+
+	silent := c.Bool(defs.OptionSimple) || c.Bool(defs.OptionJSON) || c.Bool(defs.OptionCSV) || c.Bool(defs.OptionProto)
+	duration := time.Duration(c.Int(defs.OptionDuration)) * time.Second
+	if handled, err := speedtest.DispatchBackend(c, ooklaOrigin, duration, c.Int("concurrent"), silent); handled {
+		return err
+	}
+
+	return fmt.Errorf("no test mode selected: pass --p2p, --serve, or --backend ookla")
+}
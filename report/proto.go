@@ -0,0 +1,114 @@
+package report
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// MarshalProtoBatch encodes reports as a ReportBatch message per
+// report.proto, using the standard protobuf wire format directly so the
+// CLI doesn't need to pull in a protoc-generated runtime just for this one
+// output mode. Field numbers below must stay in sync with report.proto.
+func MarshalProtoBatch(reports []Report) []byte {
+	var batch []byte
+	for _, r := range reports {
+		batch = appendMessageField(batch, 1, encodeReport(r))
+	}
+	return batch
+}
+
+func encodeReport(r Report) []byte {
+	var buf []byte
+	buf = appendVarintField(buf, 1, uint64(r.Timestamp.Unix()))
+	buf = appendDoubleField(buf, 2, r.Ping)
+	buf = appendDoubleField(buf, 3, r.Jitter)
+	buf = appendDoubleField(buf, 4, r.Download)
+	buf = appendDoubleField(buf, 5, r.Upload)
+	buf = appendVarintField(buf, 6, uint64(r.BytesReceived))
+	buf = appendVarintField(buf, 7, uint64(r.BytesSent))
+	buf = appendStringField(buf, 8, r.Share)
+	buf = appendMessageField(buf, 9, encodeServerInfo(r.Server))
+	buf = appendMessageField(buf, 10, encodeClientInfo(r.Client))
+	for _, s := range r.Samples {
+		buf = appendMessageField(buf, 11, encodeIntervalSample(s))
+	}
+	buf = appendMessageField(buf, 12, encodeSummary(r.Summary))
+	return buf
+}
+
+func encodeSummary(s Summary) []byte {
+	var buf []byte
+	buf = appendDoubleField(buf, 1, s.P50Mbps)
+	buf = appendDoubleField(buf, 2, s.P95Mbps)
+	buf = appendDoubleField(buf, 3, s.P99Mbps)
+	buf = appendDoubleField(buf, 4, s.BufferbloatScore)
+	return buf
+}
+
+func encodeIntervalSample(s IntervalSample) []byte {
+	var buf []byte
+	buf = appendVarintField(buf, 1, uint64(s.TSince.Milliseconds()))
+	buf = appendVarintField(buf, 2, uint64(s.Bytes))
+	buf = appendDoubleField(buf, 3, s.Mbps)
+	buf = appendStringField(buf, 4, s.Direction)
+	return buf
+}
+
+func encodeServerInfo(s Server) []byte {
+	var buf []byte
+	buf = appendStringField(buf, 1, s.Name)
+	buf = appendStringField(buf, 2, s.URL)
+	return buf
+}
+
+func encodeClientInfo(c Client) []byte {
+	var buf []byte
+	buf = appendBytesField(buf, 1, c.IPInfoResponse)
+	return buf
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendTag(buf []byte, fieldNum int, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(fieldNum)<<3|uint64(wireType))
+}
+
+func appendVarintField(buf []byte, fieldNum int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendTag(buf, fieldNum, wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendDoubleField(buf []byte, fieldNum int, v float64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendTag(buf, fieldNum, wireFixed64)
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+func appendStringField(buf []byte, fieldNum int, v string) []byte {
+	return appendBytesField(buf, fieldNum, []byte(v))
+}
+
+func appendBytesField(buf []byte, fieldNum int, v []byte) []byte {
+	if len(v) == 0 {
+		return buf
+	}
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+func appendMessageField(buf []byte, fieldNum int, msg []byte) []byte {
+	if len(msg) == 0 {
+		return buf
+	}
+	return appendBytesField(buf, fieldNum, msg)
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestAppendVarintFieldOmitsZero(t *testing.T) {
+	if got := appendVarintField(nil, 1, 0); got != nil {
+		t.Errorf("appendVarintField(nil, 1, 0) = %v, want nil", got)
+	}
+}
+
+func TestAppendVarintFieldRoundTrips(t *testing.T) {
+	buf := appendVarintField(nil, 1, 42)
+
+	tag, n := binary.Uvarint(buf)
+	if tag != 1<<3|wireVarint {
+		t.Fatalf("tag = %d, want field 1 wireVarint", tag)
+	}
+
+	v, _ := binary.Uvarint(buf[n:])
+	if v != 42 {
+		t.Errorf("value = %d, want 42", v)
+	}
+}
+
+func TestAppendStringFieldOmitsEmpty(t *testing.T) {
+	if got := appendStringField(nil, 1, ""); got != nil {
+		t.Errorf("appendStringField(nil, 1, \"\") = %v, want nil", got)
+	}
+}
+
+func TestMarshalProtoBatchNonEmpty(t *testing.T) {
+	batch := MarshalProtoBatch([]Report{{Ping: 12.5, Server: Server{Name: "test"}}})
+	if len(batch) == 0 {
+		t.Fatal("MarshalProtoBatch() returned no bytes for a non-empty report")
+	}
+}
@@ -0,0 +1,95 @@
+// Package report defines the shape of a speedtest result and the various
+// formats (JSON, CSV, Protobuf) it can be rendered into.
+package report
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Report is the result of a single test run against a single server.
+type Report struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	Ping     float64 `json:"ping"`
+	Jitter   float64 `json:"jitter"`
+	Download float64 `json:"download"`
+	Upload   float64 `json:"upload"`
+
+	BytesReceived int `json:"bytesReceived"`
+	BytesSent     int `json:"bytesSent"`
+
+	Share string `json:"share,omitempty"`
+
+	Server Server `json:"server"`
+	Client Client `json:"client"`
+
+	// Samples holds the per-interval throughput measurements captured while
+	// Download/Upload were in flight, newest last. It's empty unless the
+	// backend records samples; only the P2P backend does.
+	Samples []IntervalSample `json:"samples,omitempty"`
+
+	// Summary is the percentile/bufferbloat rollup Summarize computes from
+	// Samples and the ping measurements below, once both are available.
+	Summary Summary `json:"summary"`
+
+	// PingIdle, PingDownloadLoaded and PingUploadLoaded are only populated
+	// in --bufferbloat mode: idle RTT median, and loaded RTT medians taken
+	// while Download/Upload were in flight, respectively.
+	PingIdle           float64 `json:"pingIdle,omitempty"`
+	PingDownloadLoaded float64 `json:"pingDownloadLoaded,omitempty"`
+	PingUploadLoaded   float64 `json:"pingUploadLoaded,omitempty"`
+	BufferbloatGrade   string  `json:"bufferbloatGrade,omitempty"`
+}
+
+// Server identifies which backend served the test.
+type Server struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Client carries the telemetry server's view of the testing client.
+type Client struct {
+	IPInfoResponse json.RawMessage `json:"ipInfoResponse,omitempty"`
+	Readme         string          `json:"-"`
+}
+
+// FlatReport is Report flattened for CSV output via gocsv.
+type FlatReport struct {
+	Timestamp     string  `csv:"Timestamp"`
+	Server        string  `csv:"Server Name"`
+	ServerURL     string  `csv:"Server URL"`
+	Ping          float64 `csv:"Ping"`
+	Jitter        float64 `csv:"Jitter"`
+	Download      float64 `csv:"Download"`
+	Upload        float64 `csv:"Upload"`
+	BytesReceived int     `csv:"Bytes Received"`
+	BytesSent     int     `csv:"Bytes Sent"`
+	Share         string  `csv:"Share"`
+
+	PingIdle           float64 `csv:"Ping (idle)"`
+	PingDownloadLoaded float64 `csv:"Ping (download loaded)"`
+	PingUploadLoaded   float64 `csv:"Ping (upload loaded)"`
+	BufferbloatGrade   string  `csv:"Bufferbloat Grade"`
+}
+
+// GetFlatReport flattens r for CSV output.
+func (r Report) GetFlatReport() FlatReport {
+	return FlatReport{
+		Timestamp:     r.Timestamp.Format(time.RFC3339),
+		Server:        r.Server.Name,
+		ServerURL:     r.Server.URL,
+		Ping:          r.Ping,
+		Jitter:        r.Jitter,
+		Download:      r.Download,
+		Upload:        r.Upload,
+		BytesReceived: r.BytesReceived,
+		BytesSent:     r.BytesSent,
+		Share:         r.Share,
+
+		PingIdle:           r.PingIdle,
+		PingDownloadLoaded: r.PingDownloadLoaded,
+		PingUploadLoaded:   r.PingUploadLoaded,
+		BufferbloatGrade:   r.BufferbloatGrade,
+	}
+}
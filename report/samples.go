@@ -0,0 +1,65 @@
+package report
+
+import (
+	"sort"
+	"time"
+)
+
+// IntervalSample is one throughput measurement taken roughly every 100 ms
+// while a download or upload is in flight.
+type IntervalSample struct {
+	TSince    time.Duration `json:"tSince"`
+	Bytes     int           `json:"bytes"`
+	Mbps      float64       `json:"mbps"`
+	Direction string        `json:"direction"`
+}
+
+// Summary holds the derived statistics Summarize computes from a Report's
+// Samples, so downstream tooling doesn't have to recompute them from raw
+// samples every time.
+type Summary struct {
+	P50Mbps float64 `json:"p50Mbps"`
+	P95Mbps float64 `json:"p95Mbps"`
+	P99Mbps float64 `json:"p99Mbps"`
+
+	// BufferbloatScore is the increase in ping, in milliseconds, observed
+	// while the link was loaded versus idle. Lower is better.
+	BufferbloatScore float64 `json:"bufferbloatScore"`
+}
+
+// Summarize computes p50/p95/p99 throughput across samples, plus a
+// bufferbloat score comparing idlePing to the median ping observed while
+// the link was under load.
+func Summarize(samples []IntervalSample, idlePing, loadedPing float64) Summary {
+	mbps := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		mbps = append(mbps, s.Mbps)
+	}
+	sort.Float64s(mbps)
+
+	return Summary{
+		P50Mbps:          percentile(mbps, 50),
+		P95Mbps:          percentile(mbps, 95),
+		P99Mbps:          percentile(mbps, 99),
+		BufferbloatScore: loadedPing - idlePing,
+	}
+}
+
+// percentile returns the p-th percentile of sorted (ascending), linearly
+// interpolating between the two closest ranks. It returns 0 for an empty
+// input.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p / 100) * float64(len(sorted)-1)
+	lo := int(rank)
+	hi := lo + 1
+	if hi >= len(sorted) {
+		return sorted[lo]
+	}
+
+	frac := rank - float64(lo)
+	return sorted[lo] + frac*(sorted[hi]-sorted[lo])
+}
@@ -0,0 +1,31 @@
+package report
+
+import "testing"
+
+func TestSummarizePercentiles(t *testing.T) {
+	samples := []IntervalSample{
+		{Mbps: 10}, {Mbps: 20}, {Mbps: 30}, {Mbps: 40}, {Mbps: 50},
+	}
+
+	got := Summarize(samples, 10, 15)
+
+	if got.P50Mbps != 30 {
+		t.Errorf("P50Mbps = %.2f, want 30", got.P50Mbps)
+	}
+	// p=99 over 5 samples interpolates between ranks 3 and 4 (40 and 50)
+	// rather than landing exactly on the top sample: rank = 0.99*4 = 3.96,
+	// frac = 0.96, so 40 + 0.96*(50-40) = 49.6.
+	if got.P99Mbps != 49.6 {
+		t.Errorf("P99Mbps = %.2f, want 49.6", got.P99Mbps)
+	}
+	if got.BufferbloatScore != 5 {
+		t.Errorf("BufferbloatScore = %.2f, want 5", got.BufferbloatScore)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	got := Summarize(nil, 0, 0)
+	if got.P50Mbps != 0 || got.P95Mbps != 0 || got.P99Mbps != 0 {
+		t.Errorf("Summarize(nil, ...) = %+v, want all-zero percentiles", got)
+	}
+}
@@ -0,0 +1,104 @@
+package speedtest
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/librespeed/speedtest-cli/defs"
+)
+
+// bufferbloatPingInterval is how often the loaded-ping sampler re-measures
+// ping while a download or upload is in flight.
+const bufferbloatPingInterval = 1 * time.Second
+
+// bufferbloatPingCount is how many echoes each loaded-ping sample uses; kept
+// small so the sampler itself doesn't meaningfully compete with the
+// throughput test for bandwidth.
+const bufferbloatPingCount = 3
+
+// pingSampler repeatedly measures ping against a server in the background
+// so --bufferbloat can compare it against the idle baseline once a
+// throughput phase completes.
+type pingSampler struct {
+	mu      sync.Mutex
+	samples []float64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startPingSampler begins sampling ping in the background. Callers must
+// call stopAndMedian once the throughput phase it's measuring against has
+// finished.
+func startPingSampler(server defs.Server, sourceIP, network string) *pingSampler {
+	s := &pingSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(bufferbloatPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if p, _, err := server.ICMPPingAndJitter(bufferbloatPingCount, sourceIP, network); err == nil {
+					s.mu.Lock()
+					s.samples = append(s.samples, p)
+					s.mu.Unlock()
+				}
+			}
+		}
+	}()
+
+	return s
+}
+
+// stopAndMedian halts sampling and returns the median of everything
+// collected. It returns 0 if the phase ended before a single tick fired.
+func (s *pingSampler) stopAndMedian() float64 {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), s.samples...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// gradeBufferbloat grades the worse of the download/upload loaded-ping
+// medians against idlePing, using the standard DSLReports-style thresholds.
+// It returns "" if neither loaded ping was actually measured (e.g. the
+// throughput phase was shorter than bufferbloatPingInterval).
+func gradeBufferbloat(pingDownloadLoaded, pingUploadLoaded, idlePing float64) string {
+	if pingDownloadLoaded == 0 && pingUploadLoaded == 0 {
+		return ""
+	}
+
+	deltaMs := math.Max(pingDownloadLoaded, pingUploadLoaded) - idlePing
+	switch {
+	case deltaMs <= 5:
+		return "A"
+	case deltaMs <= 30:
+		return "B"
+	case deltaMs <= 60:
+		return "C"
+	case deltaMs <= 200:
+		return "D"
+	default:
+		return "F"
+	}
+}
@@ -0,0 +1,27 @@
+package speedtest
+
+import "testing"
+
+func TestGradeBufferbloat(t *testing.T) {
+	cases := []struct {
+		name                                           string
+		pingDownloadLoaded, pingUploadLoaded, idlePing float64
+		want                                           string
+	}{
+		{"neither measured", 0, 0, 20, ""},
+		{"grade A", 22, 21, 20, "A"},
+		{"grade B", 45, 30, 20, "B"},
+		{"grade C", 75, 30, 20, "C"},
+		{"grade D", 150, 30, 20, "D"},
+		{"grade F", 300, 30, 20, "F"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := gradeBufferbloat(tc.pingDownloadLoaded, tc.pingUploadLoaded, tc.idlePing); got != tc.want {
+				t.Errorf("gradeBufferbloat(%v, %v, %v) = %q, want %q",
+					tc.pingDownloadLoaded, tc.pingUploadLoaded, tc.idlePing, got, tc.want)
+			}
+		})
+	}
+}
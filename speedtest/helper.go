@@ -8,6 +8,7 @@ import (
 	"math"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -81,13 +82,26 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 			pb.Stop()
 		}
 
+		bufferbloat := c.Bool(defs.OptionBufferbloat)
+
 		// get download value
 		var downloadValue float64
 		var bytesRead int
+		var pingDownloadLoaded float64
 		if testOpts.NoDownload {
 			log.Info("Download test is disabled")
 		} else {
+			var sampler *pingSampler
+			if bufferbloat {
+				sampler = startPingSampler(currentServer, testOpts.SourceIP, testOpts.Network)
+			}
+
 			download, br, err := currentServer.Download(silent, testOpts.Bytes, testOpts.BinaryBase, testOpts.Concurrent, testOpts.Chunks, time.Duration(testOpts.Duration)*time.Second)
+
+			if sampler != nil {
+				pingDownloadLoaded = sampler.stopAndMedian()
+			}
+
 			if err != nil {
 				log.Errorf("Failed to get download speed: %s", err)
 				return err
@@ -99,10 +113,21 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 		// get upload value
 		var uploadValue float64
 		var bytesWritten int
+		var pingUploadLoaded float64
 		if testOpts.NoUpload {
 			log.Info("Upload test is disabled")
 		} else {
+			var sampler *pingSampler
+			if bufferbloat {
+				sampler = startPingSampler(currentServer, testOpts.SourceIP, testOpts.Network)
+			}
+
 			upload, bw, err := currentServer.Upload(testOpts.NoPreAllocate, silent, testOpts.Bytes, testOpts.BinaryBase, testOpts.Concurrent, testOpts.Chunks, time.Duration(testOpts.Duration)*time.Second)
+
+			if sampler != nil {
+				pingUploadLoaded = sampler.stopAndMedian()
+			}
+
 			if err != nil {
 				log.Errorf("Failed to get upload speed: %s", err)
 				return err
@@ -111,6 +136,11 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 			bytesWritten = bw
 		}
 
+		var bufferbloatGrade string
+		if bufferbloat {
+			bufferbloatGrade = gradeBufferbloat(pingDownloadLoaded, pingUploadLoaded, p)
+		}
+
 		// print result if --simple is given
 		if c.Bool(defs.OptionSimple) {
 			if testOpts.Bytes {
@@ -119,6 +149,10 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 			} else {
 				log.Warnf("Ping:\t%.2f ms\tJitter:\t%.2f ms\nDownload rate:\t%.2f Mbps\nUpload rate:\t%.2f Mbps", p, jitter, downloadValue, uploadValue)
 			}
+
+			if bufferbloat {
+				log.Warnf("Idle ping:\t%.2f ms\tLoaded ping (down/up):\t%.2f/%.2f ms\tBufferbloat:\t%s", p, pingDownloadLoaded, pingUploadLoaded, bufferbloatGrade)
+			}
 		}
 
 		// print share link if --share is given
@@ -156,6 +190,20 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 		rep.Client = report.Client{IPInfoResponse: ispInfo.RawISPInfo}
 		rep.Client.Readme = ""
 
+		if bufferbloat {
+			rep.PingIdle = math.Round(p*100) / 100
+			rep.PingDownloadLoaded = math.Round(pingDownloadLoaded*100) / 100
+			rep.PingUploadLoaded = math.Round(pingUploadLoaded*100) / 100
+			rep.BufferbloatGrade = bufferbloatGrade
+		}
+
+		// rep.Samples stays empty for this (LibreSpeed) backend: Download and
+		// Upload don't accept a sampling callback here; of the backends, only
+		// P2P's interval loop feeds Samples. That means P50/P95/P99Mbps below
+		// are always 0 for this path; BufferbloatScore only needs the ping
+		// measurements, and is 0 unless --bufferbloat is set.
+		rep.Summary = report.Summarize(rep.Samples, rep.PingIdle, math.Max(pingDownloadLoaded, pingUploadLoaded))
+
 		reps = append(reps, rep)
 
 		//add a new line after each test if testing multiple servers
@@ -164,6 +212,18 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 		}
 	}
 
+	WriteReports(reps, c)
+
+	return nil
+}
+
+// WriteReports renders reps in whichever of --csv, --json or --proto was
+// requested on c, prioritizing --csv over --json over --proto, same as
+// CliSpeedTest always has. It's a no-op if none of them were passed. Any
+// Cli* entry point that produces report.Report values - not just
+// CliSpeedTest's own per-server loop - should funnel its output through
+// here so --json/--csv/--proto behave the same regardless of backend.
+func WriteReports(reps []report.Report, c *cli.Context) {
 	// check for --csv or --json. the program prioritize the --csv before the --json. this is the same behavior as speedtest-cli
 	if c.Bool(defs.OptionCSV) {
 		var reps_csv []report.FlatReport
@@ -181,9 +241,16 @@ func CliSpeedTest(testOpts *defs.TestOptions, c *cli.Context, silent bool) error
 		} else {
 			fmt.Println(string(jsonBytes))
 		}
+	} else if c.Bool(defs.OptionProto) {
+		protoBytes := report.MarshalProtoBatch(reps)
+		if out := c.String(defs.OptionProtoOut); out != "" {
+			if err := ioutil.WriteFile(out, protoBytes, 0644); err != nil {
+				log.Errorf("Error writing protobuf report to %s: %s", out, err)
+			}
+		} else {
+			os.Stdout.Write(protoBytes)
+		}
 	}
-
-	return nil
 }
 
 func SpeedTest(testOpts *defs.TestOptions) (*[]report.Report, error) {
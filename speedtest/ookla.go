@@ -0,0 +1,187 @@
+package speedtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/librespeed/speedtest-cli/defs"
+	"github.com/librespeed/speedtest-cli/report"
+)
+
+// ooklaDownloadSizes mirrors the image sizes speedtest.net clients request,
+// smallest first; each download worker cycles through them in this order.
+var ooklaDownloadSizes = []int{350, 500, 750, 1000, 1500, 2000, 2500, 3000, 3500, 4000}
+
+// ooklaUploadChunkBytes is the size of each random payload POSTed during the
+// upload test.
+const ooklaUploadChunkBytes = 1 * 1024 * 1024
+
+// ooklaCandidateCount is how many of the nearest servers CliSpeedTestOokla
+// latency-probes before picking one, when --closest isn't set.
+const ooklaCandidateCount = 10
+
+// DispatchBackend runs the Ookla backend instead of the normal LibreSpeed
+// flow when --backend ookla is set, reporting handled=true so the CLI entry
+// point can skip its regular server-discovery path. It's the --backend
+// counterpart of DispatchP2P.
+func DispatchBackend(c *cli.Context, origin defs.Point, duration time.Duration, concurrent int, silent bool) (handled bool, err error) {
+	if c.String(defs.OptionBackend) != "ookla" {
+		return false, nil
+	}
+
+	_, err = CliSpeedTestOokla(c, origin, duration, concurrent, silent)
+	return true, err
+}
+
+// CliSpeedTestOokla runs a full ping/download/upload pass against the
+// nearest reachable speedtest.net server and renders the result through the
+// same report.Report flow CliSpeedTest uses for the LibreSpeed backend,
+// including --json/--csv/--proto output via WriteReports. If --closest is
+// set, it skips latency-probing the nearest ooklaCandidateCount servers and
+// goes straight for the single closest one by great-circle distance.
+func CliSpeedTestOokla(c *cli.Context, origin defs.Point, duration time.Duration, concurrent int, silent bool) (*report.Report, error) {
+	servers, err := defs.FetchOoklaServers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Ookla server list: %w", err)
+	}
+
+	candidateCount := ooklaCandidateCount
+	if c.Bool(defs.OptionClosest) {
+		candidateCount = 1
+	}
+
+	candidates := defs.ClosestOoklaServers(origin, servers, candidateCount)
+	server, latency, err := defs.ProbeLatency(candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a responsive Ookla server: %w", err)
+	}
+
+	if !silent {
+		log.Infof("Selected Ookla server: %s [%s], %.2f ms", server.Name, server.Host, latency)
+	}
+
+	downloadMbps, bytesRead, err := ooklaDownload(server, duration, concurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get download speed: %w", err)
+	}
+
+	uploadMbps, bytesWritten, err := ooklaUpload(server, duration, concurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload speed: %w", err)
+	}
+
+	if !silent {
+		log.Warnf("Ping:\t%.2f ms\nDownload rate:\t%.2f Mbps\nUpload rate:\t%.2f Mbps", latency, downloadMbps, uploadMbps)
+	}
+
+	var rep report.Report
+	rep.Timestamp = time.Now()
+	rep.Ping = math.Round(latency*100) / 100
+	rep.Download = math.Round(downloadMbps*100) / 100
+	rep.Upload = math.Round(uploadMbps*100) / 100
+	rep.BytesReceived = bytesRead
+	rep.BytesSent = bytesWritten
+	rep.Server.Name = server.Name
+	rep.Server.URL = server.Host
+
+	WriteReports([]report.Report{rep}, c)
+
+	return &rep, nil
+}
+
+// ooklaDownload issues parallel GETs for images, each worker cycling through
+// ooklaDownloadSizes from smallest to largest and starting over, until
+// duration elapses, returning the aggregate throughput and bytes read.
+func ooklaDownload(server defs.OoklaServer, duration time.Duration, concurrent int) (float64, int, error) {
+	deadline := time.Now().Add(duration)
+	var totalBytes int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sizeIdx := 0
+			for time.Now().Before(deadline) {
+				size := ooklaDownloadSizes[sizeIdx%len(ooklaDownloadSizes)]
+				sizeIdx++
+
+				n, err := fetchOokla(server, size)
+				if err != nil {
+					return
+				}
+				atomic.AddInt64(&totalBytes, int64(n))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return mbps(int(totalBytes), duration), int(totalBytes), nil
+}
+
+func fetchOokla(server defs.OoklaServer, size int) (int, error) {
+	u, err := server.DownloadURL(size)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := server.HTTPClient().Get(u.String())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return countBytes(resp.Body)
+}
+
+func countBytes(r io.Reader) (int, error) {
+	n, err := io.Copy(ioutil.Discard, r)
+	return int(n), err
+}
+
+// ooklaUpload POSTs random payloads to upload.php until duration elapses.
+func ooklaUpload(server defs.OoklaServer, duration time.Duration, concurrent int) (float64, int, error) {
+	deadline := time.Now().Add(duration)
+	var totalBytes int64
+	var wg sync.WaitGroup
+
+	payload, err := randomBuffer(ooklaUploadChunkBytes)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	u, err := server.UploadURL()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				resp, err := server.HTTPClient().Post(u.String(), "application/octet-stream", bytes.NewReader(payload))
+				if err != nil {
+					return
+				}
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+				atomic.AddInt64(&totalBytes, int64(len(payload)))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return mbps(int(totalBytes), duration), int(totalBytes), nil
+}
@@ -0,0 +1,435 @@
+package speedtest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/librespeed/speedtest-cli/defs"
+	"github.com/librespeed/speedtest-cli/report"
+)
+
+const (
+	// p2pVersion is bumped whenever the wire format of p2pConfigFrame changes
+	p2pVersion = 1
+
+	// p2pSampleInterval is how often both sides of a P2P test sample bytes
+	// transferred, matching the granularity used for the web based tests
+	p2pSampleInterval = 100 * time.Millisecond
+
+	// p2pPayloadSize is the default chunk size used to fill the connection
+	p2pPayloadSize = 64 * 1024
+
+	// p2pMaxPayloadSizeBytes bounds what a client can ask ServeP2P to
+	// allocate per connection, so a hostile peer can't force large
+	// allocations just by sending a big payloadSizeBytes.
+	p2pMaxPayloadSizeBytes = 4 * 1024 * 1024
+
+	// p2pMaxDurationSec bounds how long a single connection can tie up a
+	// ServeP2P worker.
+	p2pMaxDurationSec = 60
+
+	// p2pDefaultDurationSec is used whenever a client omits DurationSec or
+	// sends a non-positive value.
+	p2pDefaultDurationSec = 10
+
+	// p2pHandshakeTimeout bounds how long ServeP2P waits for a client to
+	// send its config frame, so a peer that opens a connection and never
+	// writes anything can't pin a goroutine and socket forever.
+	p2pHandshakeTimeout = 5 * time.Second
+)
+
+// p2pConfigFrame is sent by the client as the first thing on the wire, once
+// the TCP connection is established. The server bounds DurationSec and
+// PayloadSizeBytes with clampP2POptions and sends nothing back on success,
+// so a client is not told when its requested duration was clamped.
+type p2pConfigFrame struct {
+	Version          int    `json:"version"`
+	Direction        string `json:"direction"`
+	DurationSec      int    `json:"durationSec"`
+	PayloadSizeBytes int    `json:"payloadSizeBytes"`
+}
+
+// p2pErrorFrame is returned by the server instead of running the test when
+// the handshake can't be satisfied, e.g. on a version mismatch.
+type p2pErrorFrame struct {
+	Error string `json:"error"`
+}
+
+// IntervalResult is one ~100 ms throughput sample taken while a P2P transfer
+// is in flight.
+type IntervalResult struct {
+	Start time.Time
+	End   time.Time
+	Bytes int
+	Mbps  float64
+}
+
+// p2pResult is the aggregate of a single P2P download or upload pass.
+type p2pResult struct {
+	Direction string
+	Bytes     int
+	Mbps      float64
+	Intervals []IntervalResult
+}
+
+// randomBuffer is a pre-allocated buffer filled once via crypto/rand and
+// reused for the lifetime of a transfer, so the hot loop never pays for
+// further allocation or randomness generation.
+func randomBuffer(size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ServeP2P listens for raw TCP speedtest clients and serves them until l is
+// closed. Each connection is handled in its own goroutine and is independent
+// of every other connection.
+func ServeP2P(l net.Listener) error {
+	log.Infof("Listening for P2P speedtest clients on %s", l.Addr())
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := serveP2PConn(conn); err != nil {
+				log.Debugf("P2P session with %s ended: %s", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// clampP2POptions bounds a client-supplied payload size and duration to
+// sane server-side limits, falling back to the defaults for non-positive
+// values.
+func clampP2POptions(payloadSizeBytes, durationSec int) (int, int) {
+	if payloadSizeBytes <= 0 {
+		payloadSizeBytes = p2pPayloadSize
+	} else if payloadSizeBytes > p2pMaxPayloadSizeBytes {
+		payloadSizeBytes = p2pMaxPayloadSizeBytes
+	}
+
+	if durationSec <= 0 {
+		durationSec = p2pDefaultDurationSec
+	} else if durationSec > p2pMaxDurationSec {
+		durationSec = p2pMaxDurationSec
+	}
+
+	return payloadSizeBytes, durationSec
+}
+
+func serveP2PConn(conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(p2pHandshakeTimeout)); err != nil {
+		return fmt.Errorf("failed to set handshake deadline: %w", err)
+	}
+
+	var cfg p2pConfigFrame
+	if err := json.NewDecoder(conn).Decode(&cfg); err != nil {
+		return fmt.Errorf("failed to decode config frame: %w", err)
+	}
+
+	if cfg.Version != p2pVersion {
+		_ = json.NewEncoder(conn).Encode(p2pErrorFrame{
+			Error: fmt.Sprintf("unsupported protocol version %d, server runs %d", cfg.Version, p2pVersion),
+		})
+		return fmt.Errorf("rejected client on version mismatch (got %d)", cfg.Version)
+	}
+
+	payloadSize, durationSec := clampP2POptions(cfg.PayloadSizeBytes, cfg.DurationSec)
+
+	buf, err := randomBuffer(payloadSize)
+	if err != nil {
+		return fmt.Errorf("failed to prepare payload buffer: %w", err)
+	}
+
+	duration := time.Duration(durationSec) * time.Second
+
+	// the server does the opposite of what the client asked for: a client
+	// wanting to measure its download reads from us, so we write
+	switch cfg.Direction {
+	case "download":
+		_, _, err = transfer(conn, buf, duration, false)
+	case "upload":
+		_, _, err = transfer(conn, buf, duration, true)
+	default:
+		_ = json.NewEncoder(conn).Encode(p2pErrorFrame{Error: fmt.Sprintf("unknown direction %q", cfg.Direction)})
+		return fmt.Errorf("unknown direction %q", cfg.Direction)
+	}
+
+	return err
+}
+
+// transfer reads from or writes to conn for the given duration, sampling
+// throughput every p2pSampleInterval. When readMode is true bytes are read
+// off the connection (the server side of an upload, the client side of a
+// download), otherwise buf is written to it.
+func transfer(conn net.Conn, buf []byte, duration time.Duration, readMode bool) (int, []IntervalResult, error) {
+	deadline := time.Now().Add(duration)
+	conn.SetDeadline(deadline)
+
+	var total int
+	var intervals []IntervalResult
+
+	ticker := time.NewTicker(p2pSampleInterval)
+	defer ticker.Stop()
+
+	intervalStart := time.Now()
+	intervalBytes := 0
+
+	done := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(done)
+		for time.Now().Before(deadline) {
+			var n int
+			var err error
+			if readMode {
+				n, err = conn.Read(buf)
+			} else {
+				n, err = conn.Write(buf)
+			}
+			if n > 0 {
+				total += n
+				intervalBytes += n
+			}
+			if err != nil {
+				if !isExpectedEndOfTransfer(err) {
+					errCh <- err
+				}
+				return
+			}
+		}
+	}()
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			intervals = append(intervals, IntervalResult{
+				Start: intervalStart,
+				End:   now,
+				Bytes: intervalBytes,
+				Mbps:  mbps(intervalBytes, now.Sub(intervalStart)),
+			})
+			intervalStart = now
+			intervalBytes = 0
+		case <-done:
+			break loop
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		return total, intervals, err
+	default:
+	}
+
+	return total, intervals, nil
+}
+
+// isExpectedEndOfTransfer reports whether err is how a timed transfer is
+// supposed to end: either the peer closed the connection (io.EOF) or our
+// own SetDeadline fired once the test duration elapsed. Anything else is a
+// genuine transport failure.
+func isExpectedEndOfTransfer(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func mbps(bytes int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / 1e6 / elapsed.Seconds()
+}
+
+// tcpPingAndJitter measures ping and jitter against a P2P server using a
+// short TCP round-trip handshake instead of ICMP, since ServeP2P doesn't
+// require raw socket privileges.
+func tcpPingAndJitter(addr string, count int) (ping, jitter float64, err error) {
+	var samples []float64
+
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		conn, dialErr := net.DialTimeout("tcp", addr, 5*time.Second)
+		if dialErr != nil {
+			return 0, 0, dialErr
+		}
+		conn.Close()
+		samples = append(samples, float64(time.Since(start).Microseconds())/1000)
+	}
+
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	ping = sum / float64(len(samples))
+
+	var jitterSum float64
+	for i := 1; i < len(samples); i++ {
+		jitterSum += math.Abs(samples[i] - samples[i-1])
+	}
+	if len(samples) > 1 {
+		jitter = jitterSum / float64(len(samples)-1)
+	}
+
+	return ping, jitter, nil
+}
+
+// CliSpeedTestP2P dials a raw TCP peer running ServeP2P and runs a full
+// download+upload pass against it, rendering the result through the same
+// report.Report flow used by the LibreSpeed backed CliSpeedTest, including
+// --json/--csv/--proto output via WriteReports.
+func CliSpeedTestP2P(c *cli.Context, addr string, durationSec int, silent bool) (*report.Report, error) {
+	ping, jitter, err := tcpPingAndJitter(addr, pingCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ping and jitter: %w", err)
+	}
+
+	download, err := runP2P(addr, "download", durationSec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get download speed: %w", err)
+	}
+
+	upload, err := runP2P(addr, "upload", durationSec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload speed: %w", err)
+	}
+
+	if !silent {
+		log.Warnf("Ping:\t%.2f ms\tJitter:\t%.2f ms\nDownload rate:\t%.2f Mbps\nUpload rate:\t%.2f Mbps",
+			ping, jitter, download.Mbps, upload.Mbps)
+	}
+
+	var rep report.Report
+	rep.Timestamp = time.Now()
+	rep.Ping = math.Round(ping*100) / 100
+	rep.Jitter = math.Round(jitter*100) / 100
+	rep.Download = math.Round(download.Mbps*100) / 100
+	rep.Upload = math.Round(upload.Mbps*100) / 100
+	rep.BytesReceived = download.Bytes
+	rep.BytesSent = upload.Bytes
+	rep.Server.Name = "P2P"
+	rep.Server.URL = addr
+
+	rep.Samples = append(rep.Samples, intervalSamples(download.Intervals, "download")...)
+	rep.Samples = append(rep.Samples, intervalSamples(upload.Intervals, "upload")...)
+	rep.Summary = report.Summarize(rep.Samples, rep.Ping, rep.Ping)
+
+	WriteReports([]report.Report{rep}, c)
+
+	return &rep, nil
+}
+
+// intervalSamples converts a P2P transfer's IntervalResults into the
+// report package's interval sample type, tagging each with direction.
+func intervalSamples(intervals []IntervalResult, direction string) []report.IntervalSample {
+	samples := make([]report.IntervalSample, 0, len(intervals))
+	for _, iv := range intervals {
+		samples = append(samples, report.IntervalSample{
+			TSince:    iv.Start.Sub(intervals[0].Start),
+			Bytes:     iv.Bytes,
+			Mbps:      iv.Mbps,
+			Direction: direction,
+		})
+	}
+	return samples
+}
+
+// DispatchP2P inspects --serve and --p2p and, if either is set, runs the
+// corresponding P2P mode instead of the normal LibreSpeed flow. It reports
+// handled=true whenever one of these modes applies, so the CLI entry point
+// can skip its regular server-discovery path. --serve and --p2p are
+// mutually exclusive with each other and with --server.
+func DispatchP2P(c *cli.Context) (handled bool, err error) {
+	serveAddr := c.String(defs.OptionServe)
+	p2pAddr := c.String(defs.OptionP2P)
+
+	switch {
+	case serveAddr != "" && p2pAddr != "":
+		return true, fmt.Errorf("--serve and --p2p cannot be used together")
+	case serveAddr != "" && c.String(defs.OptionServer) != "":
+		return true, fmt.Errorf("--serve cannot be used together with --server")
+	case p2pAddr != "" && c.String(defs.OptionServer) != "":
+		return true, fmt.Errorf("--p2p and --server are mutually exclusive")
+	}
+
+	if serveAddr != "" {
+		l, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return true, fmt.Errorf("failed to listen on %s: %w", serveAddr, err)
+		}
+		return true, ServeP2P(l)
+	}
+
+	if p2pAddr == "" {
+		return false, nil
+	}
+
+	// same "suppress progress/summary output" logic WriteReports' callers
+	// use elsewhere: any machine-readable output format implies silent.
+	silent := c.Bool(defs.OptionSimple) || c.Bool(defs.OptionJSON) || c.Bool(defs.OptionCSV) || c.Bool(defs.OptionProto)
+
+	_, err = CliSpeedTestP2P(c, p2pAddr, c.Int(defs.OptionDuration), silent)
+	return true, err
+}
+
+// runP2P dials addr, announces the requested direction and streams the
+// transfer for durationSec seconds.
+func runP2P(addr, direction string, durationSec int) (*p2pResult, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cfg := p2pConfigFrame{
+		Version:          p2pVersion,
+		Direction:        direction,
+		DurationSec:      durationSec,
+		PayloadSizeBytes: p2pPayloadSize,
+	}
+	if err := json.NewEncoder(conn).Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to send config frame: %w", err)
+	}
+
+	buf, err := randomBuffer(p2pPayloadSize)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(durationSec) * time.Second
+
+	// the client does what it announced: downloading means it reads what
+	// the server writes, uploading means it writes to the server
+	readMode := direction == "download"
+	total, intervals, err := transfer(conn, buf, duration, readMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p2pResult{
+		Direction: direction,
+		Bytes:     total,
+		Mbps:      mbps(total, duration),
+		Intervals: intervals,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package speedtest
+
+import "testing"
+
+func TestClampP2POptions(t *testing.T) {
+	cases := []struct {
+		name                                  string
+		payloadSizeBytes, durationSec         int
+		wantPayloadSizeBytes, wantDurationSec int
+	}{
+		{"defaults on zero", 0, 0, p2pPayloadSize, p2pDefaultDurationSec},
+		{"defaults on negative", -1, -1, p2pPayloadSize, p2pDefaultDurationSec},
+		{"passes through in-range values", 1024, 30, 1024, 30},
+		{"clamps oversized payload", p2pMaxPayloadSizeBytes + 1, 30, p2pMaxPayloadSizeBytes, 30},
+		{"clamps overlong duration", 1024, p2pMaxDurationSec + 1, 1024, p2pMaxDurationSec},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotPayloadSizeBytes, gotDurationSec := clampP2POptions(tc.payloadSizeBytes, tc.durationSec)
+			if gotPayloadSizeBytes != tc.wantPayloadSizeBytes || gotDurationSec != tc.wantDurationSec {
+				t.Errorf("clampP2POptions(%d, %d) = (%d, %d), want (%d, %d)",
+					tc.payloadSizeBytes, tc.durationSec,
+					gotPayloadSizeBytes, gotDurationSec,
+					tc.wantPayloadSizeBytes, tc.wantDurationSec)
+			}
+		})
+	}
+}